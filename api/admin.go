@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"go.zzfly.net/geminiapi/util/log"
+	"go.zzfly.net/geminiapi/util/redis"
+	"go.zzfly.net/geminiapi/util/trace"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminListKeys handles GET /admin/keys, listing masked API keys and health.
+func AdminListKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := getCtx(r, w)
+	if !authorizeAdmin(ctx, w, r) {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeAdminJSON(ctx, w, http.StatusMethodNotAllowed, Response{Code: 405, Body: "method not allowed"})
+		return
+	}
+
+	infos, err := redis.ListKeyHealth(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to list key health: %v", err)
+		writeAdminJSON(ctx, w, http.StatusInternalServerError, Response{Code: 500, Body: "could not list keys"})
+		return
+	}
+
+	writeAdminJSON(ctx, w, http.StatusOK, infos)
+}
+
+// AdminPurgeKeys handles POST /admin/keys/purge?scope=lapsed, removing
+// quarantined keys older than LAPSED_KEY_HORIZON_SECONDS (default 24h).
+func AdminPurgeKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := getCtx(r, w)
+	if !authorizeAdmin(ctx, w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeAdminJSON(ctx, w, http.StatusMethodNotAllowed, Response{Code: 405, Body: "method not allowed"})
+		return
+	}
+
+	scope := getFromQuery(r, "scope", "lapsed")
+	if scope != "lapsed" {
+		writeAdminJSON(ctx, w, http.StatusBadRequest, Response{Code: 400, Body: "unsupported scope: " + scope})
+		return
+	}
+
+	horizon := time.Duration(getEnvAsInt("LAPSED_KEY_HORIZON_SECONDS", 86400)) * time.Second
+	purged, err := redis.PurgeLapsedKeys(ctx, horizon)
+	if err != nil {
+		log.Error(ctx, "Failed to purge lapsed keys: %v", err)
+		writeAdminJSON(ctx, w, http.StatusInternalServerError, Response{Code: 500, Body: "could not purge keys"})
+		return
+	}
+
+	log.Info(ctx, "Purged %d lapsed API keys", purged)
+	writeAdminJSON(ctx, w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// authorizeAdmin checks the Authorization: Bearer token against ADMIN_SECRET,
+// writing an error response and returning false if unauthorized.
+func authorizeAdmin(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
+	secret := os.Getenv("ADMIN_SECRET")
+	if secret == "" {
+		log.Error(ctx, "ADMIN_SECRET not configured, refusing admin request")
+		writeAdminJSON(ctx, w, http.StatusServiceUnavailable, Response{Code: 503, Body: "admin API not configured"})
+		return false
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		writeAdminJSON(ctx, w, http.StatusUnauthorized, Response{Code: 401, Body: "unauthorized"})
+		return false
+	}
+
+	return true
+}
+
+// writeAdminJSON writes v as a JSON response with the given status code.
+func writeAdminJSON(ctx context.Context, w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Trace-Id", trace.GetTraceId(ctx))
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error(ctx, "could not write admin response: %v", err)
+	}
+}
+
+// getEnvAsInt returns the value of an environment variable as an integer or a default value
+func getEnvAsInt(key string, defaultValue int) int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
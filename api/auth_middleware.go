@@ -0,0 +1,31 @@
+package api
+
+import (
+	"go.zzfly.net/geminiapi/util/auth"
+	"go.zzfly.net/geminiapi/util/log"
+	"net/http"
+)
+
+// WithAuth wraps next so every request is authenticated by authenticator
+// first. The resolved Principal is exposed in the request context so
+// MainHandle's logging (and the per-key health subsystem) can attribute the
+// call. Requests to /healthz bypass authentication entirely.
+func WithAuth(authenticator auth.Authenticator, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			next(w, r)
+			return
+		}
+
+		ctx := getCtx(r, w)
+
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			log.Error(ctx, "Authentication failed: %v", err)
+			doStdResponse(ctx, Response{Code: 401, Body: "Unauthorized: " + err.Error()})
+			return
+		}
+
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
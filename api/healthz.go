@@ -0,0 +1,12 @@
+package api
+
+import (
+	"net/http"
+)
+
+// HealthCheck handles GET /healthz for infra health checks. It always
+// bypasses authentication, see WithAuth.
+func HealthCheck(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
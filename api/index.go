@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"go.zzfly.net/geminiapi/handler"
+	"go.zzfly.net/geminiapi/util/auth"
 	"go.zzfly.net/geminiapi/util/log"
 	"go.zzfly.net/geminiapi/util/trace"
+	"io"
 	"net/http"
 )
 
@@ -24,6 +26,9 @@ func MainHandle(w http.ResponseWriter, r *http.Request) {
 	log.Info(ctx, "Received request - Method: %s, Path: %s", r.Method, r.URL.Path)
 	log.Info(ctx, "Request headers: %v", logHeaders(r.Header))
 	log.Info(ctx, "Request query parameters: %v", r.URL.Query())
+	if principal, ok := auth.GetPrincipal(r.Context()); ok {
+		log.Info(ctx, "Authenticated principal: %s", principal.Subject)
+	}
 
 	// Get the API key from query parameters
 	apiKey := getFromQuery(r, "key", "")
@@ -123,6 +128,11 @@ func doGeminiResponse(ctx context.Context, resp *handler.GeminiResponse) {
 	// Set the status code from the Gemini API response
 	w.WriteHeader(resp.StatusCode)
 
+	if resp.Streaming {
+		streamGeminiResponse(ctx, w, resp)
+		return
+	}
+
 	// Write the response body
 	_, err := w.Write(resp.Body)
 	if err != nil {
@@ -130,6 +140,43 @@ func doGeminiResponse(ctx context.Context, resp *handler.GeminiResponse) {
 	}
 }
 
+// streamGeminiResponse copies the upstream body through to the client as it
+// arrives, flushing after every chunk so streamGenerateContent/SSE clients
+// see incremental output instead of the full response at once. Closing the
+// client connection cancels ctx, which in turn cancels the upstream request.
+func streamGeminiResponse(ctx context.Context, w http.ResponseWriter, resp *handler.GeminiResponse) {
+	defer resp.BodyReader.Close()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Error(ctx, "response writer does not support flushing, falling back to buffered copy")
+		if _, err := io.Copy(w, resp.BodyReader); err != nil {
+			log.Error(ctx, "could not copy streaming gemini response: %v", err)
+		}
+		return
+	}
+
+	if _, err := io.Copy(flushWriter{w: w, f: flusher}, resp.BodyReader); err != nil {
+		log.Error(ctx, "could not stream gemini response: %v", err)
+	}
+}
+
+// flushWriter flushes the underlying ResponseWriter after every write so
+// chunked transfer reaches the client immediately instead of being buffered.
+type flushWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	fw.f.Flush()
+	return n, nil
+}
+
 // logHeaders returns a map of headers for logging
 func logHeaders(headers http.Header) map[string]string {
 	result := make(map[string]string)
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	goredis "github.com/redis/go-redis/v9"
+	"go.zzfly.net/geminiapi/util/log"
+	"go.zzfly.net/geminiapi/util/redis"
+	"go.zzfly.net/geminiapi/util/trace"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheKeyPrefix namespaces cached responses in Redis: gemini-proxy:cache:<hash>
+const cacheKeyPrefix = "gemini-proxy:cache:"
+
+// maxCacheableBodyBytes caps how large a request body we'll bother caching.
+const maxCacheableBodyBytes = 64 * 1024
+
+// cachedResponsePayload is the JSON shape stored under a cache key.
+type cachedResponsePayload struct {
+	StatusCode int         `json:"statusCode"`
+	Headers    http.Header `json:"headers"`
+	Body       []byte      `json:"body"`
+}
+
+// prepareCache decides whether in's request is eligible for the response
+// cache and, if so, returns its cache key.
+func prepareCache(in *SendToGeminiInput, parse *url.URL) (string, bool, error) {
+	if cacheTTL() <= 0 {
+		return "", false, nil
+	}
+
+	if !isCacheableRequest(in.Method, parse.Path) {
+		return "", false, nil
+	}
+
+	if hasNoStore(in.Headers) {
+		return "", false, nil
+	}
+
+	bodyBytes, err := drainCacheableBody(in)
+	if err != nil {
+		return "", false, err
+	}
+	if bodyBytes == nil {
+		// Body exceeded maxCacheableBodyBytes; in.Payload was restored as-is.
+		return "", false, nil
+	}
+
+	query := parse.Query()
+	query.Del("key")
+
+	h := sha256.New()
+	h.Write([]byte(in.Method))
+	h.Write([]byte("\n"))
+	h.Write([]byte(parse.Path))
+	h.Write([]byte("?"))
+	h.Write([]byte(query.Encode()))
+	h.Write([]byte("\n"))
+	h.Write(canonicalizeJSON(bodyBytes))
+
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+// drainCacheableBody reads in.Payload fully and restores it for later use by
+// the upstream request. Returns nil, nil if the body is over
+// maxCacheableBodyBytes.
+func drainCacheableBody(in *SendToGeminiInput) ([]byte, error) {
+	if in.Payload == nil {
+		return []byte{}, nil
+	}
+
+	limited := io.LimitReader(in.Payload, maxCacheableBodyBytes+1)
+	body, err := io.ReadAll(limited)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > maxCacheableBodyBytes {
+		in.Payload = io.MultiReader(bytes.NewReader(body), in.Payload)
+		return nil, nil
+	}
+
+	in.Payload = bytes.NewReader(body)
+	return body, nil
+}
+
+// canonicalizeJSON re-serializes JSON bodies so equivalent payloads hash to
+// the same cache key. Non-JSON bodies are hashed as-is.
+func canonicalizeJSON(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+
+	return canonical
+}
+
+// isCacheableRequest reports whether method/path is one of the safe,
+// idempotent Gemini calls the response cache covers.
+func isCacheableRequest(method, path string) bool {
+	if method == http.MethodGet {
+		if path == "/v1beta/models" {
+			return true
+		}
+		return strings.HasPrefix(path, "/v1beta/models/") && !strings.Contains(path, ":")
+	}
+
+	if method == http.MethodPost {
+		return strings.HasSuffix(path, ":countTokens") || strings.HasSuffix(path, ":embedContent")
+	}
+
+	return false
+}
+
+// hasNoStore reports whether the client asked us not to cache this request.
+func hasNoStore(headers http.Header) bool {
+	if headers == nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(headers.Get("Cache-Control")), "no-store")
+}
+
+// getCachedResponse looks up cacheKey in Redis and returns the stored
+// response, marked with X-Cache: HIT.
+func getCachedResponse(ctx context.Context, cacheKey string) (*GeminiResponse, bool) {
+	client := redis.GetClient()
+	if client == nil {
+		return nil, false
+	}
+
+	raw, err := client.Get(ctx, cacheKeyPrefix+cacheKey).Result()
+	if err == goredis.Nil {
+		return nil, false
+	}
+	if err != nil {
+		log.Error(ctx, "Failed to read response cache: %v", err)
+		return nil, false
+	}
+
+	var cached cachedResponsePayload
+	if err := json.Unmarshal([]byte(raw), &cached); err != nil {
+		log.Error(ctx, "Failed to decode cached response: %v", err)
+		return nil, false
+	}
+
+	headers := cached.Headers.Clone()
+	headers.Set("X-Cache", "HIT")
+
+	return &GeminiResponse{
+		StatusCode: cached.StatusCode,
+		Headers:    headers,
+		Body:       cached.Body,
+	}, true
+}
+
+// storeCachedResponseAsync populates the response cache for cacheKey in the
+// background.
+func storeCachedResponseAsync(cacheKey string, statusCode int, headers http.Header, body []byte) {
+	go func() {
+		ctx := trace.WrapTraceInfo(context.Background())
+
+		client := redis.GetClient()
+		if client == nil {
+			return
+		}
+
+		stored := headers.Clone()
+		stored.Del("Set-Cookie")
+		stored.Del("Date")
+
+		data, err := json.Marshal(cachedResponsePayload{
+			StatusCode: statusCode,
+			Headers:    stored,
+			Body:       body,
+		})
+		if err != nil {
+			log.Error(ctx, "Failed to encode response for caching: %v", err)
+			return
+		}
+
+		if err := client.SetNX(ctx, cacheKeyPrefix+cacheKey, data, cacheTTL()).Err(); err != nil {
+			log.Error(ctx, "Failed to populate response cache: %v", err)
+		}
+	}()
+}
+
+// cacheTTL returns the response cache TTL, overridable with
+// CACHE_TTL_SECONDS. A value of 0 disables caching entirely.
+func cacheTTL() time.Duration {
+	seconds := 300
+	if v := os.Getenv("CACHE_TTL_SECONDS"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			seconds = parsed
+		}
+	}
+	return time.Duration(seconds) * time.Second
+}
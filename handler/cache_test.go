@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsCacheableRequest(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodGet, "/v1beta/models", true},
+		{http.MethodGet, "/v1beta/models/gemini-pro", true},
+		{http.MethodGet, "/v1beta/models/gemini-pro:generateContent", false},
+		{http.MethodPost, "/v1beta/models/gemini-pro:countTokens", true},
+		{http.MethodPost, "/v1beta/models/gemini-pro:embedContent", true},
+		{http.MethodPost, "/v1beta/models/gemini-pro:generateContent", false},
+		{http.MethodDelete, "/v1beta/models/gemini-pro", false},
+	}
+
+	for _, c := range cases {
+		if got := isCacheableRequest(c.method, c.path); got != c.want {
+			t.Errorf("isCacheableRequest(%s, %s) = %v, want %v", c.method, c.path, got, c.want)
+		}
+	}
+}
+
+func TestCanonicalizeJSON(t *testing.T) {
+	a := canonicalizeJSON([]byte(`{"b": 2, "a": 1}`))
+	b := canonicalizeJSON([]byte(`{"a":1,"b":2}`))
+	if string(a) != string(b) {
+		t.Errorf("canonicalizeJSON produced different output for equivalent JSON: %q vs %q", a, b)
+	}
+}
+
+func TestCanonicalizeJSONNonJSON(t *testing.T) {
+	raw := []byte("not json")
+	if got := canonicalizeJSON(raw); string(got) != string(raw) {
+		t.Errorf("canonicalizeJSON(%q) = %q, want unchanged", raw, got)
+	}
+}
+
+func TestCanonicalizeJSONEmpty(t *testing.T) {
+	if got := canonicalizeJSON(nil); len(got) != 0 {
+		t.Errorf("canonicalizeJSON(nil) = %q, want empty", got)
+	}
+}
@@ -1,27 +1,52 @@
 package handler
 
 import (
+	"container/list"
 	"context"
 	"fmt"
 	"go.zzfly.net/geminiapi/util/log"
 	"go.zzfly.net/geminiapi/util/redis"
 	"go.zzfly.net/geminiapi/util/trace"
+	"golang.org/x/net/proxy"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
 const PROXY_URL = "https://generativelanguage.googleapis.com"
 
+// maxCachedProxyClients bounds how many per-proxy http.Client instances are cached.
+const maxCachedProxyClients = 64
+
+// nonStreamingRequestTimeout bounds non-streaming calls end-to-end, applied
+// by cancelling the request context (see SendToGemini) rather than
+// Client.Timeout.
+const nonStreamingRequestTimeout = 30 * time.Second
+
+// dialTimeout and responseHeaderTimeout bound connection setup and the
+// upstream status line/headers, without bounding a streaming body's lifetime.
+const (
+	dialTimeout           = 10 * time.Second
+	responseHeaderTimeout = 30 * time.Second
+	idleConnTimeout       = 90 * time.Second
+)
+
 var httpClient = http.Client{
-	Timeout: 30 * time.Second,
+	Transport: newProxyTransport(nil),
 }
 
+// proxyClients is an LRU of http.Client instances keyed by raw proxy URL.
+var proxyClients = newProxyClientCache(maxCachedProxyClients)
+
 type SendToGeminiInput struct {
 	Url         string
 	ContentType string
 	APIKey      string
+	ProxyURL    string
 	Payload     io.Reader
 	Method      string
 	Headers     http.Header
@@ -32,17 +57,36 @@ type GeminiResponse struct {
 	Body       []byte
 	StatusCode int
 	Headers    http.Header
+	// Streaming is true when BodyReader carries a still-open upstream body
+	// instead of Body.
+	Streaming  bool
+	BodyReader io.ReadCloser
 }
 
 // SendToGemini sends a request to gemini
 func SendToGemini(ctx context.Context, in SendToGeminiInput) (*GeminiResponse, error) {
 	// Construir a URL completa usando o caminho da requisição original
 	fullUrl := PROXY_URL + in.Url
-	apiKey := getAPIKey(in)
 	parse, err := url.Parse(fullUrl)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse url: %w", err)
 	}
+
+	cacheKey, cacheable, err := prepareCache(&in, parse)
+	if err != nil {
+		log.Error(ctx, "Failed to prepare response cache, skipping: %v", err)
+		cacheable = false
+	}
+
+	if cacheable {
+		if cached, ok := getCachedResponse(ctx, cacheKey); ok {
+			log.Info(ctx, "Cache hit for %s %s", in.Method, in.Url)
+			return cached, nil
+		}
+	}
+
+	apiKey, proxyURL := getAPIKeyAndProxy(in)
+
 	// Preservar os parâmetros de consulta originais e adicionar a chave API
 	query := parse.Query()
 	// Remove any existing key parameter from the URL
@@ -59,9 +103,24 @@ func SendToGemini(ctx context.Context, in SendToGeminiInput) (*GeminiResponse, e
 	log.Info(ctx, "using api key: %s", maskAPIKey(apiKey))
 	log.Info(ctx, "Final request URL: %s", fullUrl)
 
+	// Only bound non-streaming calls; cancelReqCtx is invoked on the
+	// non-streaming path below, never on the streaming return path, and the
+	// timeout timer is stopped there, so neither can cut off a body read
+	// still in progress.
+	reqCtx := ctx
+	var cancelReqCtx context.CancelFunc
+	var reqTimer *time.Timer
+	if !isStreamingRequest(in.Url) {
+		reqCtx, cancelReqCtx = context.WithCancel(ctx)
+		reqTimer = time.AfterFunc(nonStreamingRequestTimeout, cancelReqCtx)
+	}
+
 	// Create a new request with the appropriate method
-	req, err := http.NewRequestWithContext(ctx, in.Method, fullUrl, in.Payload)
+	req, err := http.NewRequestWithContext(reqCtx, in.Method, fullUrl, in.Payload)
 	if err != nil {
+		if cancelReqCtx != nil {
+			cancelReqCtx()
+		}
 		log.Error(ctx, "Failed to create request: %v", err)
 		return nil, fmt.Errorf("could not create request: %w", err)
 	}
@@ -82,13 +141,35 @@ func SendToGemini(ctx context.Context, in SendToGeminiInput) (*GeminiResponse, e
 		}
 	}
 
-	// Send the request
-	resp, err := httpClient.Do(req)
+	// Send the request, routing through the key's outbound proxy if it has one
+	client := clientForProxy(ctx, proxyURL)
+	resp, err := client.Do(req)
 	if err != nil {
+		if cancelReqCtx != nil {
+			cancelReqCtx()
+		}
 		log.Error(ctx, "HTTP request failed: %v", err)
 		return nil, fmt.Errorf("could not send request: %w", err)
 	}
 	log.Info(ctx, "Response status code: %d", resp.StatusCode)
+	reportKeyHealthAsync(apiKey, resp.StatusCode)
+
+	if isStreamingRequest(in.Url) || isEventStream(resp.Header) {
+		log.Info(ctx, "Streaming response passthrough for: %s", in.Url)
+		if reqTimer != nil {
+			reqTimer.Stop()
+		}
+		return &GeminiResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Header,
+			Streaming:  true,
+			BodyReader: resp.Body,
+		}, nil
+	}
+
+	if cancelReqCtx != nil {
+		defer cancelReqCtx()
+	}
 
 	defer resp.Body.Close()
 
@@ -99,6 +180,10 @@ func SendToGemini(ctx context.Context, in SendToGeminiInput) (*GeminiResponse, e
 		return nil, fmt.Errorf("could not read response body: %w", err)
 	}
 
+	if cacheable && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		storeCachedResponseAsync(cacheKey, resp.StatusCode, resp.Header, body)
+	}
+
 	// Return the complete response
 	return &GeminiResponse{
 		Body:       body,
@@ -107,22 +192,188 @@ func SendToGemini(ctx context.Context, in SendToGeminiInput) (*GeminiResponse, e
 	}, nil
 }
 
-// getAPIKey returns the api key from the input or from Redis/env
-func getAPIKey(in SendToGeminiInput) string {
-	// If API key is provided in the request, use it
+// reportKeyHealthAsync records this call's outcome against apiKey's health
+// in the background, mirroring storeCachedResponseAsync.
+func reportKeyHealthAsync(apiKey string, statusCode int) {
+	status := redis.KeyStatusOK
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusForbidden:
+		status = redis.KeyStatusRateLimited
+	case statusCode >= 500:
+		status = redis.KeyStatusServerError
+	}
+
+	go func() {
+		ctx := trace.WrapTraceInfo(context.Background())
+		if err := redis.ReportKeyHealth(ctx, apiKey, status); err != nil {
+			log.Error(ctx, "Failed to report API key health: %v", err)
+		}
+	}()
+}
+
+// isStreamingRequest reports whether rawUrl targets a streaming endpoint.
+func isStreamingRequest(rawUrl string) bool {
+	parse, err := url.Parse(rawUrl)
+	if err != nil {
+		return strings.Contains(rawUrl, ":streamGenerateContent")
+	}
+
+	if strings.Contains(parse.Path, ":streamGenerateContent") {
+		return true
+	}
+
+	return parse.Query().Get("alt") == "sse"
+}
+
+// isEventStream reports whether the upstream response came back as SSE.
+func isEventStream(headers http.Header) bool {
+	return strings.HasPrefix(headers.Get("Content-Type"), "text/event-stream")
+}
+
+// getAPIKeyAndProxy returns the api key and its outbound proxy URL (if any)
+// from the input, or from Redis/env when the caller didn't set an API key.
+func getAPIKeyAndProxy(in SendToGeminiInput) (string, string) {
+	// If API key is provided in the request, use it as-is
 	if in.APIKey != "" {
-		return in.APIKey
+		return in.APIKey, in.ProxyURL
 	}
 
 	// Get API key from Redis using round-robin selection with trace info
 	ctx := trace.WrapTraceInfo(context.Background())
-	apiKey, err := redis.GetAPIKey(ctx)
+	apiKey, proxyURL, err := redis.GetAPIKey(ctx)
 	if err != nil {
 		log.Error(ctx, "Failed to get API key from Redis: %v", err)
 		// Fallback to environment variable is handled inside redis.GetAPIKey
 	}
 
-	return apiKey
+	return apiKey, proxyURL
+}
+
+// clientForProxy returns the http.Client to use for proxyURL, building and
+// caching one on first use. An empty proxyURL uses the default client.
+func clientForProxy(ctx context.Context, proxyURL string) *http.Client {
+	if proxyURL == "" {
+		return &httpClient
+	}
+
+	if client, ok := proxyClients.get(proxyURL); ok {
+		return client
+	}
+
+	client, err := buildProxyClient(proxyURL)
+	if err != nil {
+		log.Error(ctx, "Failed to build client for proxy %s, falling back to default: %v", proxyURL, err)
+		return &httpClient
+	}
+
+	proxyClients.add(proxyURL, client)
+	return client
+}
+
+// buildProxyClient builds an http.Client that routes through proxyURL.
+// Supports http://, https:// and socks5://.
+func buildProxyClient(proxyURL string) (*http.Client, error) {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse proxy url: %w", err)
+	}
+
+	var transport *http.Transport
+
+	switch parsed.Scheme {
+	case "http", "https":
+		transport = newProxyTransport(http.ProxyURL(parsed))
+	case "socks5":
+		dialer, err := proxy.FromURL(parsed, &net.Dialer{Timeout: dialTimeout})
+		if err != nil {
+			return nil, fmt.Errorf("could not build socks5 dialer: %w", err)
+		}
+		transport = newProxyTransport(nil)
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", parsed.Scheme)
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// newProxyTransport builds an *http.Transport with the shared dial/response-
+// header/idle-connection timeouts, optionally routing through proxyFunc.
+func newProxyTransport(proxyFunc func(*http.Request) (*url.URL, error)) *http.Transport {
+	return &http.Transport{
+		Proxy:                 proxyFunc,
+		DialContext:           (&net.Dialer{Timeout: dialTimeout}).DialContext,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		IdleConnTimeout:       idleConnTimeout,
+	}
+}
+
+// proxyClientCache is a thread-safe LRU cache of http.Client instances keyed
+// by proxy URL.
+type proxyClientCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type proxyClientEntry struct {
+	proxyURL string
+	client   *http.Client
+}
+
+func newProxyClientCache(capacity int) *proxyClientCache {
+	return &proxyClientCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *proxyClientCache) get(proxyURL string) (*http.Client, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[proxyURL]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return elem.Value.(*proxyClientEntry).client, true
+}
+
+func (c *proxyClientCache) add(proxyURL string, client *http.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[proxyURL]; ok {
+		elem.Value.(*proxyClientEntry).client = client
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	elem := c.order.PushFront(&proxyClientEntry{proxyURL: proxyURL, client: client})
+	c.entries[proxyURL] = elem
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			evicted := oldest.Value.(*proxyClientEntry)
+			delete(c.entries, evicted.proxyURL)
+			closeIdleConnections(evicted.client)
+		}
+	}
+}
+
+// closeIdleConnections releases a client's pooled idle connections.
+func closeIdleConnections(client *http.Client) {
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+	}
 }
 
 // maskAPIKey masks an API key for secure logging
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"go.zzfly.net/geminiapi/api"
+	"go.zzfly.net/geminiapi/util/auth"
 	"go.zzfly.net/geminiapi/util/log"
 	"go.zzfly.net/geminiapi/util/redis"
 	"go.zzfly.net/geminiapi/util/trace"
@@ -19,9 +20,22 @@ func main() {
 		// Continue execution even if Redis initialization fails
 	}
 
+	// Build the client-authentication middleware selected by PROXY_AUTH_MODE
+	authenticator, err := auth.New(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to initialize authenticator: %v", err)
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", api.HealthCheck)
+	mux.HandleFunc("/admin/keys", api.AdminListKeys)
+	mux.HandleFunc("/admin/keys/purge", api.AdminPurgeKeys)
+	mux.HandleFunc("/", api.WithAuth(authenticator, api.MainHandle))
+
 	// Listen on port 8080
 	log.Info(ctx, "Starting server on port 8080")
-	err = http.ListenAndServe(":8080", http.HandlerFunc(api.MainHandle))
+	err = http.ListenAndServe(":8080", mux)
 	if err != nil {
 		panic(err)
 	}
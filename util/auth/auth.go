@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+const ctxKeyPrincipal = "authPrincipal"
+
+// Principal identifies the caller a request has been authenticated as.
+type Principal struct {
+	Subject string
+	Groups  []string
+}
+
+// Authenticator validates an inbound request and resolves it to a Principal.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// New builds the Authenticator selected by PROXY_AUTH_MODE (none|static|oidc).
+func New(ctx context.Context) (Authenticator, error) {
+	switch getEnv("PROXY_AUTH_MODE", "none") {
+	case "static":
+		tokens := splitAndTrim(getEnv("PROXY_AUTH_TOKENS", ""), ",")
+		return NewStaticTokenAuthenticator(tokens), nil
+	case "oidc":
+		return NewOIDCAuthenticator(
+			ctx,
+			getEnv("OIDC_ISSUER", ""),
+			getEnv("OIDC_AUDIENCE", ""),
+			getEnv("OIDC_USER_CLAIM", "sub"),
+			getEnv("OIDC_GROUPS_CLAIM", ""),
+		)
+	default:
+		return noopAuthenticator{}, nil
+	}
+}
+
+// noopAuthenticator lets every request through as an anonymous principal,
+// preserving today's behavior when PROXY_AUTH_MODE is unset or "none".
+type noopAuthenticator struct{}
+
+func (noopAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	return Principal{Subject: "anonymous"}, nil
+}
+
+// WithPrincipal returns a context carrying principal for later retrieval by GetPrincipal.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, ctxKeyPrincipal, principal)
+}
+
+// GetPrincipal returns the principal stored in ctx, if any.
+func GetPrincipal(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(ctxKeyPrincipal).(Principal)
+	return principal, ok
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+		return ""
+	}
+	return header[len(prefix):]
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"net/http"
+)
+
+// OIDCAuthenticator validates bearer JWTs against an OIDC issuer's JWKS.
+type OIDCAuthenticator struct {
+	verifier    *oidc.IDTokenVerifier
+	userClaim   string
+	groupsClaim string
+}
+
+// NewOIDCAuthenticator builds an OIDCAuthenticator for the given issuer and
+// audience, discovering the issuer's JWKS endpoint via OIDC discovery.
+func NewOIDCAuthenticator(ctx context.Context, issuer, audience, userClaim, groupsClaim string) (*OIDCAuthenticator, error) {
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("could not discover OIDC provider: %w", err)
+	}
+
+	if userClaim == "" {
+		userClaim = "sub"
+	}
+
+	return &OIDCAuthenticator{
+		verifier:    provider.Verifier(&oidc.Config{ClientID: audience}),
+		userClaim:   userClaim,
+		groupsClaim: groupsClaim,
+	}, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *OIDCAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return Principal{}, fmt.Errorf("missing bearer token")
+	}
+
+	idToken, err := a.verifier.Verify(r.Context(), token)
+	if err != nil {
+		return Principal{}, fmt.Errorf("could not verify token: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return Principal{}, fmt.Errorf("could not read token claims: %w", err)
+	}
+
+	subject, _ := claims[a.userClaim].(string)
+	if subject == "" {
+		return Principal{}, fmt.Errorf("token missing %s claim", a.userClaim)
+	}
+
+	return Principal{Subject: subject, Groups: stringGroups(claims[a.groupsClaim])}, nil
+}
+
+// stringGroups coerces a decoded groups claim (typically []interface{} of
+// strings) into a []string, ignoring the claim entirely if it's absent or an
+// unexpected shape.
+func stringGroups(v interface{}) []string {
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	groups := make([]string, 0, len(raw))
+	for _, g := range raw {
+		if s, ok := g.(string); ok {
+			groups = append(groups, s)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,296 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.zzfly.net/geminiapi/util/log"
+)
+
+const (
+	// healthKeyPrefix namespaces the per-key health hash: gemini-proxy:health:<keyHash>
+	healthKeyPrefix = "gemini-proxy:health:"
+	// quarantineKey is a ZSET of keyHash members scored by the unix time their
+	// quarantine expires, so expired entries fall out with ZREMRANGEBYSCORE.
+	quarantineKey = "gemini-proxy:quarantine"
+	// consecutiveFailThreshold is how many consecutive 429/403/5xx responses a
+	// key can accumulate before it's quarantined.
+	consecutiveFailThreshold = 3
+)
+
+// KeyHealthStatus is the outcome of a single call made with an API key,
+// reported back to ReportKeyHealth once the upstream response is known.
+type KeyHealthStatus int
+
+const (
+	KeyStatusOK KeyHealthStatus = iota
+	KeyStatusRateLimited
+	KeyStatusServerError
+)
+
+// KeyHealthInfo is a point-in-time snapshot of one key's health, as returned
+// by ListKeyHealth for the admin keys endpoint.
+type KeyHealthInfo struct {
+	MaskedKey   string `json:"maskedKey"`
+	ProxyURL    string `json:"proxyUrl,omitempty"`
+	OK          int64  `json:"ok"`
+	Fail        int64  `json:"fail"`
+	Last429At   int64  `json:"last429At,omitempty"`
+	Last5xxAt   int64  `json:"last5xxAt,omitempty"`
+	Quarantined bool   `json:"quarantined"`
+}
+
+// quarantineTTL is how long a tripped key is skipped by round-robin before
+// it's re-enqueued, overridable with QUARANTINE_TTL_SECONDS.
+func quarantineTTL() time.Duration {
+	return time.Duration(getEnvAsInt("QUARANTINE_TTL_SECONDS", 300)) * time.Second
+}
+
+// ReportKeyHealth records the outcome of a call made with apiKey and
+// quarantines the key once it trips consecutiveFailThreshold.
+func ReportKeyHealth(ctx context.Context, apiKey string, status KeyHealthStatus) error {
+	if apiKey == "" {
+		return nil
+	}
+
+	client := GetClient()
+	if client == nil {
+		return nil
+	}
+
+	hash := keyHash(apiKey)
+	healthKey := healthKeyPrefix + hash
+	now := time.Now().Unix()
+
+	if status == KeyStatusOK {
+		_, err := client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.HSet(ctx, healthKey, "consecutive_fail", 0)
+			pipe.HIncrBy(ctx, healthKey, "ok", 1)
+			return nil
+		})
+		return err
+	}
+
+	var consecutiveFail *goredis.IntCmd
+	_, err := client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		if status == KeyStatusRateLimited {
+			pipe.HSet(ctx, healthKey, "last_429_at", now)
+		} else {
+			pipe.HSet(ctx, healthKey, "last_5xx_at", now)
+		}
+		pipe.HIncrBy(ctx, healthKey, "fail", 1)
+		consecutiveFail = pipe.HIncrBy(ctx, healthKey, "consecutive_fail", 1)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if shouldQuarantine(consecutiveFail.Val()) {
+		log.Info(ctx, "Quarantining API key %s after %d consecutive failures", maskAPIKey(apiKey), consecutiveFail.Val())
+		return client.ZAdd(ctx, quarantineKey, goredis.Z{
+			Score:  float64(time.Now().Add(quarantineTTL()).Unix()),
+			Member: hash,
+		}).Err()
+	}
+
+	return nil
+}
+
+// shouldQuarantine reports whether consecutiveFail has tripped the threshold
+// at which a key gets quarantined.
+func shouldQuarantine(consecutiveFail int64) bool {
+	return consecutiveFail >= consecutiveFailThreshold
+}
+
+// purgeExpiredQuarantine drops quarantine entries whose expiry has passed,
+// re-enqueuing those keys for round-robin selection.
+func purgeExpiredQuarantine(ctx context.Context, client goredis.UniversalClient) {
+	now := float64(time.Now().Unix())
+	if err := client.ZRemRangeByScore(ctx, quarantineKey, "-inf", formatFloat(now)).Err(); err != nil {
+		log.Error(ctx, "Failed to purge expired quarantine entries: %v", err)
+	}
+}
+
+// isQuarantined reports whether apiKey is still serving out a quarantine.
+func isQuarantined(ctx context.Context, client goredis.UniversalClient, apiKey string) (bool, error) {
+	expiresAt, err := client.ZScore(ctx, quarantineKey, keyHash(apiKey)).Result()
+	if err == goredis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return expiresAt > float64(time.Now().Unix()), nil
+}
+
+// ListKeyHealth returns health stats for every configured API key, masked
+// for safe display by the admin keys endpoint.
+func ListKeyHealth(ctx context.Context) ([]KeyHealthInfo, error) {
+	client := GetClient()
+	if client == nil {
+		return nil, nil
+	}
+
+	entries, err := client.LRange(ctx, apiKeysKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]KeyHealthInfo, 0, len(entries))
+	for _, entry := range entries {
+		var keyData map[string]string
+		if err := unmarshalKeyData(entry, &keyData); err != nil {
+			continue
+		}
+
+		apiKey := keyData["key"]
+		hash := keyHash(apiKey)
+
+		stats, err := client.HGetAll(ctx, healthKeyPrefix+hash).Result()
+		if err != nil {
+			log.Error(ctx, "Failed to read health stats for key: %v", err)
+			stats = nil
+		}
+
+		quarantined, err := isQuarantined(ctx, client, apiKey)
+		if err != nil {
+			log.Error(ctx, "Failed to check quarantine status: %v", err)
+		}
+
+		infos = append(infos, KeyHealthInfo{
+			MaskedKey:   maskAPIKey(apiKey),
+			ProxyURL:    keyData["proxy"],
+			OK:          parseInt64(stats["ok"]),
+			Fail:        parseInt64(stats["fail"]),
+			Last429At:   parseInt64(stats["last_429_at"]),
+			Last5xxAt:   parseInt64(stats["last_5xx_at"]),
+			Quarantined: quarantined,
+		})
+	}
+
+	return infos, nil
+}
+
+// PurgeLapsedKeys removes quarantined keys whose last failure is older than
+// horizon, and returns the number of keys purged.
+func PurgeLapsedKeys(ctx context.Context, horizon time.Duration) (int, error) {
+	client := GetClient()
+	if client == nil {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-horizon).Unix()
+
+	hashes, err := client.ZRange(ctx, quarantineKey, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, hash := range hashes {
+		stats, err := client.HMGet(ctx, healthKeyPrefix+hash, "last_429_at", "last_5xx_at").Result()
+		if err != nil && err != goredis.Nil {
+			log.Error(ctx, "Failed to read last fail time for quarantined key: %v", err)
+			continue
+		}
+
+		lastFail := maxInt64(parseInt64Result(stats[0]), parseInt64Result(stats[1]))
+		if lastFail == 0 || lastFail > cutoff {
+			continue
+		}
+
+		if err := removeKeyByHash(ctx, client, hash); err != nil {
+			log.Error(ctx, "Failed to purge lapsed key: %v", err)
+			continue
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+// removeKeyByHash drops the list entry, health hash and quarantine entry for
+// the key whose hash is hash.
+func removeKeyByHash(ctx context.Context, client goredis.UniversalClient, hash string) error {
+	entries, err := client.LRange(ctx, apiKeysKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		var keyData map[string]string
+		if err := unmarshalKeyData(entry, &keyData); err != nil {
+			continue
+		}
+
+		if keyHash(keyData["key"]) != hash {
+			continue
+		}
+
+		if err := client.LRem(ctx, apiKeysKey, 1, entry).Err(); err != nil {
+			return err
+		}
+		break
+	}
+
+	if err := client.Del(ctx, healthKeyPrefix+hash).Err(); err != nil {
+		return err
+	}
+
+	return client.ZRem(ctx, quarantineKey, hash).Err()
+}
+
+// keyHash returns a stable, non-reversible identifier for apiKey suitable
+// for use in Redis key/member names without exposing the key itself.
+func keyHash(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// unmarshalKeyData parses a raw Redis list entry into its key/proxy fields.
+func unmarshalKeyData(raw string, out *map[string]string) error {
+	return json.Unmarshal([]byte(raw), out)
+}
+
+// parseInt64 parses s as an int64, returning 0 for empty or invalid input.
+func parseInt64(s string) int64 {
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// parseInt64Result parses a single HMGet result field, returning 0 for a
+// missing (nil) field.
+func parseInt64Result(v interface{}) int64 {
+	s, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	return parseInt64(s)
+}
+
+// maxInt64 returns the larger of a and b.
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// formatFloat formats f without a fractional part, for use as a ZSET score
+// bound in range queries.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 0, 64)
+}
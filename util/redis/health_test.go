@@ -0,0 +1,48 @@
+package redis
+
+import "testing"
+
+func TestShouldQuarantine(t *testing.T) {
+	cases := []struct {
+		consecutiveFail int64
+		want            bool
+	}{
+		{0, false},
+		{consecutiveFailThreshold - 1, false},
+		{consecutiveFailThreshold, true},
+		{consecutiveFailThreshold + 1, true},
+	}
+
+	for _, c := range cases {
+		if got := shouldQuarantine(c.consecutiveFail); got != c.want {
+			t.Errorf("shouldQuarantine(%d) = %v, want %v", c.consecutiveFail, got, c.want)
+		}
+	}
+}
+
+func TestKeyHash(t *testing.T) {
+	if keyHash("a") != keyHash("a") {
+		t.Error("keyHash is not deterministic")
+	}
+	if keyHash("a") == keyHash("b") {
+		t.Error("keyHash collided for distinct inputs")
+	}
+}
+
+func TestParseInt64Result(t *testing.T) {
+	if got := parseInt64Result("42"); got != 42 {
+		t.Errorf("parseInt64Result(\"42\") = %d, want 42", got)
+	}
+	if got := parseInt64Result(nil); got != 0 {
+		t.Errorf("parseInt64Result(nil) = %d, want 0", got)
+	}
+}
+
+func TestMaxInt64(t *testing.T) {
+	if maxInt64(3, 5) != 5 {
+		t.Error("maxInt64(3, 5) should be 5")
+	}
+	if maxInt64(5, 3) != 5 {
+		t.Error("maxInt64(5, 3) should be 5")
+	}
+}
@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"os"
 	"strconv"
@@ -15,8 +16,9 @@ import (
 )
 
 var (
-	// Singleton instance of Redis client
-	client     *redis.Client
+	// Singleton instance of the Redis client. It's a redis.UniversalClient so
+	// standalone, Sentinel and Cluster topologies are interchangeable for callers.
+	client     redis.UniversalClient
 	clientOnce sync.Once
 	// Key for storing API keys in Redis
 	apiKeysKey = "gemini-proxy"
@@ -26,21 +28,11 @@ var (
 	posMutex = &sync.Mutex{}
 )
 
-// GetClient returns a Redis client instance
-func GetClient() *redis.Client {
+// GetClient returns a Redis client instance. The topology (standalone,
+// Sentinel or Cluster) is selected by REDIS_MODE; see newUniversalClient.
+func GetClient() redis.UniversalClient {
 	clientOnce.Do(func() {
-		// Get Redis configuration from environment variables
-		redisHost := getEnv("REDIS_HOST", "redis")
-		redisPort := getEnv("REDIS_PORT", "6379")
-		redisPassword := getEnv("REDIS_PASSWORD", "")
-		redisDB := getEnvAsInt("REDIS_DB", 0)
-
-		// Create Redis client
-		client = redis.NewClient(&redis.Options{
-			Addr:     redisHost + ":" + redisPort,
-			Password: redisPassword,
-			DB:       redisDB,
-		})
+		client = newUniversalClient()
 
 		// Test connection
 		ctx, cancel := context.WithTimeout(trace.WrapTraceInfo(context.Background()), 5*time.Second)
@@ -52,13 +44,78 @@ func GetClient() *redis.Client {
 			// Set client to nil so we can fall back to environment variables
 			client = nil
 		} else {
-			log.Info(ctx, "Successfully connected to Redis at %s:%s", redisHost, redisPort)
+			log.Info(ctx, "Successfully connected to Redis (mode=%s)", getEnv("REDIS_MODE", "standalone"))
 		}
 	})
 
 	return client
 }
 
+// newUniversalClient builds a redis.UniversalClient for the topology selected
+// by REDIS_MODE (standalone|sentinel|cluster), sharing the same dial/read/
+// write timeouts, idle pool and TLS options across all three.
+func newUniversalClient() redis.UniversalClient {
+	mode := getEnv("REDIS_MODE", "standalone")
+
+	dialTimeout := getEnvAsDuration("REDIS_DIAL_TIMEOUT", 5*time.Second)
+	readTimeout := getEnvAsDuration("REDIS_READ_TIMEOUT", 3*time.Second)
+	writeTimeout := getEnvAsDuration("REDIS_WRITE_TIMEOUT", 3*time.Second)
+	minIdleConns := getEnvAsInt("REDIS_MIN_IDLE_CONNS", 0)
+	tlsConfig := getTLSConfig()
+
+	switch mode {
+	case "sentinel":
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			SentinelAddrs:    splitAndTrim(getEnv("REDIS_SENTINEL_ADDRS", ""), ","),
+			MasterName:       getEnv("REDIS_SENTINEL_MASTER", ""),
+			SentinelPassword: getEnv("REDIS_SENTINEL_PASSWORD", ""),
+			Password:         getEnv("REDIS_PASSWORD", ""),
+			DB:               getEnvAsInt("REDIS_DB", 0),
+			DialTimeout:      dialTimeout,
+			ReadTimeout:      readTimeout,
+			WriteTimeout:     writeTimeout,
+			MinIdleConns:     minIdleConns,
+			TLSConfig:        tlsConfig,
+		})
+	case "cluster":
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        splitAndTrim(getEnv("REDIS_CLUSTER_ADDRS", ""), ","),
+			Password:     getEnv("REDIS_PASSWORD", ""),
+			DialTimeout:  dialTimeout,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+			MinIdleConns: minIdleConns,
+			TLSConfig:    tlsConfig,
+		})
+	default:
+		redisHost := getEnv("REDIS_HOST", "redis")
+		redisPort := getEnv("REDIS_PORT", "6379")
+
+		return redis.NewClient(&redis.Options{
+			Addr:         redisHost + ":" + redisPort,
+			Password:     getEnv("REDIS_PASSWORD", ""),
+			DB:           getEnvAsInt("REDIS_DB", 0),
+			DialTimeout:  dialTimeout,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+			MinIdleConns: minIdleConns,
+			TLSConfig:    tlsConfig,
+		})
+	}
+}
+
+// getTLSConfig returns a *tls.Config when REDIS_TLS_ENABLED is set, or nil to
+// leave the connection in plaintext.
+func getTLSConfig() *tls.Config {
+	if !getEnvAsBool("REDIS_TLS_ENABLED", false) {
+		return nil
+	}
+
+	return &tls.Config{
+		InsecureSkipVerify: getEnvAsBool("REDIS_TLS_INSECURE_SKIP_VERIFY", false),
+	}
+}
+
 // InitializeAPIKeys initializes API keys in Redis from environment variable
 func InitializeAPIKeys(ctx context.Context) error {
 	client := GetClient()
@@ -131,51 +188,63 @@ func GetAPIKey(ctx context.Context) (string, string, error) {
 		return getFallbackAPIKey(), "", nil
 	}
 
-	// Get next position in round-robin fashion
-	posMutex.Lock()
-
-	// Increment position and wrap around if needed
-	currentPos = (currentPos + 1) % int(count)
-	position := currentPos
+	purgeExpiredQuarantine(ctx, client)
 
-	posMutex.Unlock()
+	// Walk the round-robin at most once per key, skipping any that are
+	// currently quarantined for repeated 429/403/5xx responses.
+	for attempt := 0; attempt < int(count); attempt++ {
+		posMutex.Lock()
+		currentPos = (currentPos + 1) % int(count)
+		position := currentPos
+		posMutex.Unlock()
 
-	// Get the API key at the current position
-	apiKeyJson, err := client.LIndex(ctx, apiKeysKey, int64(position)).Result()
-	if err != nil {
-		log.Error(ctx, "Failed to get API key from Redis: %v", err)
-		return getFallbackAPIKey(), "", nil
-	}
+		// Get the API key at the current position
+		apiKeyJson, err := client.LIndex(ctx, apiKeysKey, int64(position)).Result()
+		if err != nil {
+			log.Error(ctx, "Failed to get API key from Redis: %v", err)
+			return getFallbackAPIKey(), "", nil
+		}
 
-	// Parse the JSON to extract key and proxy
-	var keyData map[string]string
-	err = json.Unmarshal([]byte(apiKeyJson), &keyData)
-	if err != nil {
-		log.Error(ctx, "Failed to parse API key JSON from Redis: %v", err)
-		return getFallbackAPIKey(), "", nil
-	}
+		// Parse the JSON to extract key and proxy
+		var keyData map[string]string
+		if err := unmarshalKeyData(apiKeyJson, &keyData); err != nil {
+			log.Error(ctx, "Failed to parse API key JSON from Redis: %v", err)
+			return getFallbackAPIKey(), "", nil
+		}
 
-	// Extract the API key and proxy URL
-	apiKey := keyData["key"]
-	proxyURL := keyData["proxy"]
+		// Extract the API key and proxy URL
+		apiKey := keyData["key"]
+		proxyURL := keyData["proxy"]
 
-	maskedKey := ""
-	if apiKey != "" {
-		if len(apiKey) < 8 {
-			maskedKey = "<too_short>"
-		} else {
-			maskedKey = apiKey[0:4] + "****" + apiKey[len(apiKey)-4:]
+		quarantined, err := isQuarantined(ctx, client, apiKey)
+		if err != nil {
+			log.Error(ctx, "Failed to check quarantine status, using key anyway: %v", err)
+		} else if quarantined {
+			log.Info(ctx, "Skipping quarantined API key: %s", maskAPIKey(apiKey))
+			continue
 		}
-	} else {
-		maskedKey = "<empty>"
+
+		log.Info(ctx, "Got API key and proxy from Redis: key=%s, proxy=%s", maskAPIKey(apiKey), proxyURL)
+		return apiKey, proxyURL, nil
 	}
 
-	log.Info(ctx, "Got API key and proxy from Redis: key=%s, proxy=%s", maskedKey, proxyURL)
-	return apiKey, proxyURL, nil
+	log.Error(ctx, "All API keys are quarantined, falling back to environment variable")
+	return getFallbackAPIKey(), "", nil
 }
 
 // Helper functions
 
+// maskAPIKey masks an API key for secure logging
+func maskAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return "<empty>"
+	}
+	if len(apiKey) < 8 {
+		return "<too_short>"
+	}
+	return apiKey[0:4] + "****" + apiKey[len(apiKey)-4:]
+}
+
 // getEnv returns the value of an environment variable or a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -200,6 +269,32 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsDuration returns the value of an environment variable, parsed as a
+// number of milliseconds, or a default value
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	ms := getEnvAsInt(key, -1)
+	if ms < 0 {
+		return defaultValue
+	}
+
+	return time.Duration(ms) * time.Millisecond
+}
+
+// getEnvAsBool returns the value of an environment variable as a boolean or a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
 // getAPIKeysFromEnv gets API keys from environment variable
 func getAPIKeysFromEnv() []string {
 	keyStr := os.Getenv("API_KEY")